@@ -0,0 +1,150 @@
+package v1alpha1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SecretReference identifies the source Secret a SecretSync fans out.
+type SecretReference struct {
+	// Namespace of the source Secret.
+	Namespace string `json:"namespace"`
+	// Name of the source Secret.
+	Name string `json:"name"`
+}
+
+// KeyFilter restricts which keys of the source Secret are copied to destinations. Deny is applied
+// after Allow, so a key listed in both is still denied.
+type KeyFilter struct {
+	// Allow lists the only keys that may be copied. If empty, every key is allowed unless denied.
+	// +optional
+	Allow []string `json:"allow,omitempty"`
+	// Deny lists keys that must never be copied, even if also listed in Allow.
+	// +optional
+	Deny []string `json:"deny,omitempty"`
+}
+
+// TransformSpec configures how a source Secret's data is projected into a destination Secret
+// before it is written: keys may be renamed, filtered, re-encoded or rendered from a Go template,
+// and the destination type coerced to something the source Secret isn't.
+type TransformSpec struct {
+	// Rename maps source key names to destination key names.
+	// +optional
+	Rename map[string]string `json:"rename,omitempty"`
+
+	// Keys filters which (possibly renamed) keys end up in the destination Secret. Applied after
+	// Rename and before Template, so Template may still add keys Keys would otherwise have denied.
+	// +optional
+	Keys *KeyFilter `json:"keys,omitempty"`
+
+	// Base64Encode lists destination keys whose value should be base64-encoded after projection,
+	// for building secret types (e.g. a .dockercfg-style key) that expect base64-encoded fields.
+	// +optional
+	Base64Encode []string `json:"base64Encode,omitempty"`
+
+	// Template renders a destination key's value as a Go template, with the source Secret's data
+	// available as .Data, a map of key to string value (on a SecretSync, the data left after the
+	// SecretSync's own spec.keys filter). Template keys are written after Keys
+	// filtering, so a templated key is never itself filtered out.
+	// +optional
+	Template map[string]string `json:"template,omitempty"`
+
+	// Type overrides the destination Secret's type produced by this transform. Coercing to
+	// kubernetes.io/dockerconfigjson requires a non-empty .dockerconfigjson key once the rest of
+	// the transform has run.
+	// +optional
+	Type v1.SecretType `json:"type,omitempty"`
+}
+
+// SecretSyncSpec defines the desired state of a SecretSync.
+type SecretSyncSpec struct {
+	// SecretRef points at the source Secret to fan out.
+	SecretRef SecretReference `json:"secretRef"`
+
+	// DestinationNamespaceSelector selects the namespaces the source Secret is copied into.
+	DestinationNamespaceSelector metav1.LabelSelector `json:"destinationNamespaceSelector"`
+
+	// DestinationNameTemplate overrides the destination Secret's name. Defaults to the source
+	// Secret's name when empty.
+	// +optional
+	DestinationNameTemplate string `json:"destinationNameTemplate,omitempty"`
+
+	// Type overrides the destination Secret's type. Defaults to the source Secret's type when
+	// empty. Ignored if Transform sets its own Type.
+	// +optional
+	Type v1.SecretType `json:"type,omitempty"`
+
+	// Keys filters which keys of the source Secret are copied to destinations.
+	// +optional
+	Keys *KeyFilter `json:"keys,omitempty"`
+
+	// Transform projects or rewrites the source Secret's data before it is written to
+	// destinations. Equivalent to the kubed.appscode.com/transform annotation on the annotation
+	// compat path.
+	// +optional
+	Transform *TransformSpec `json:"transform,omitempty"`
+}
+
+// SyncTarget records the outcome of syncing the source Secret to a single destination namespace.
+type SyncTarget struct {
+	// Namespace the source Secret was copied into.
+	Namespace string `json:"namespace"`
+	// Name of the destination Secret.
+	Name string `json:"name"`
+	// LastSyncedResourceVersion is the source Secret's resourceVersion as of the last successful
+	// sync to this target.
+	// +optional
+	LastSyncedResourceVersion string `json:"lastSyncedResourceVersion,omitempty"`
+	// ContentHash fingerprints the destination Secret's name, type and data as of the last
+	// successful sync to this target. Unlike LastSyncedResourceVersion, it changes whenever the
+	// projected content changes for any reason, including edits to the SecretSync's own spec
+	// (transform, keys, type, destinationNameTemplate) that leave the source Secret untouched, so
+	// it is what Reconcile compares to decide whether a target still needs to be re-copied.
+	// +optional
+	ContentHash string `json:"contentHash,omitempty"`
+	// Ready is true when the destination Secret reflects ContentHash.
+	Ready bool `json:"ready"`
+	// Error holds the last sync error for this target, if any.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// SecretSyncStatus defines the observed state of a SecretSync.
+type SecretSyncStatus struct {
+	// SourceUID is the UID of the source Secret as of the last reconcile. A change here means the
+	// source was deleted and recreated, so every target must be treated as stale regardless of
+	// resourceVersion.
+	// +optional
+	SourceUID string `json:"sourceUID,omitempty"`
+
+	// Targets lists the namespaces the source Secret is currently synced to.
+	// +optional
+	Targets []SyncTarget `json:"targets,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Source",type=string,JSONPath=".spec.secretRef.name"
+
+// SecretSync declaratively fans a source Secret out to every namespace matching a label selector,
+// replacing the kubed.appscode.com/sync annotation with a typed, GitOps-friendly object.
+type SecretSync struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SecretSyncSpec   `json:"spec,omitempty"`
+	Status SecretSyncStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SecretSyncList contains a list of SecretSync.
+type SecretSyncList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SecretSync `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SecretSync{}, &SecretSyncList{})
+}
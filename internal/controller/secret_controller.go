@@ -2,24 +2,42 @@ package controller
 
 import (
 	"context"
-	"fmt"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/json"
-	"strconv"
 	"strings"
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	applycorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
-// SecretController implements a controller for secrets.
+// finalizerName is set on source secrets so that deletion can be intercepted
+// long enough to garbage-collect the copies it fanned out to.
+const finalizerName = "kubed.appscode.com/secret-sync"
+
+// fieldOwner identifies this controller's field ownership when applying destination secrets
+// server-side, so other controllers or users can add their own annotations/labels to a
+// destination without them being clobbered on every reconcile.
+const fieldOwner = "secret-sync"
+
+// SecretController implements the legacy kubed.appscode.com/sync annotation-compat path. New
+// setups should prefer the typed SecretSyncController instead.
 type SecretController struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	transforms *transformCache
 }
 
 type origin struct {
@@ -29,34 +47,64 @@ type origin struct {
 	ResourceVersion string `json:"resourceVersion"`
 }
 
+// isCurrent reports whether o was recorded from secret's present state, meaning a destination
+// Secret carrying o as its origin already reflects secret and does not need to be re-synced.
+//
+// resourceVersion is an opaque string per the Kubernetes API conventions, so it must be compared
+// with string equality, not parsed as a number. A Uid mismatch means the source secret was
+// deleted and recreated, which always requires a re-sync even if the new resourceVersion happens
+// to collide.
+func (o origin) isCurrent(secret *v1.Secret) bool {
+	return o.Uid == string(secret.UID) && o.ResourceVersion == secret.ResourceVersion
+}
+
 // +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // Reconcile handles requests to reconcile secrets.
 func (r *SecretController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
 	var secret v1.Secret
 	if err := r.Get(ctx, req.NamespacedName, &secret); err != nil {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
 	if _, found := secret.Annotations["kubed.appscode.com/origin"]; found {
-		fmt.Printf("Skipping already managed secret: %s/%s\n", secret.Namespace, secret.Name)
+		logger.V(1).Info("Skipping already managed secret", "source", req.NamespacedName, "reason", "secret carries an origin annotation")
 		// This is already a managed secret, ignore
 		return ctrl.Result{}, nil
 	}
 
 	annotationValue, found := secret.Annotations["kubed.appscode.com/sync"]
-	if !found {
-		return ctrl.Result{}, nil
+
+	if !secret.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(&secret, finalizerName) {
+			return ctrl.Result{}, nil
+		}
+
+		if err := r.gcSyncedCopies(ctx, &secret, map[string]bool{}); err != nil {
+			return ctrl.Result{}, err
+		}
+		r.transforms.evict(secret.UID)
+
+		controllerutil.RemoveFinalizer(&secret, finalizerName)
+		return ctrl.Result{}, r.Update(ctx, &secret)
 	}
 
-	parts := strings.SplitN(annotationValue, "=", 2)
-	if len(parts) != 2 {
-		return ctrl.Result{}, nil
+	var labelSelector map[string]string
+	if found {
+		if parts := strings.SplitN(annotationValue, "=", 2); len(parts) == 2 {
+			labelSelector = map[string]string{parts[0]: parts[1]}
+		}
 	}
 
-	labelSelector := map[string]string{
-		parts[0]: parts[1],
+	if labelSelector == nil {
+		// The sync annotation was removed or is malformed: treat it the same as a selector that
+		// no longer matches any namespace, so previously-synced copies are garbage collected
+		// instead of leaking until the source secret is itself deleted.
+		return ctrl.Result{}, r.gcSyncedCopies(ctx, &secret, map[string]bool{})
 	}
 
 	namespaceList := &v1.NamespaceList{}
@@ -65,81 +113,132 @@ func (r *SecretController) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{}, err
 	}
 
-	if len(namespaceList.Items) != 1 {
-		fmt.Printf("Expected exactly one namespace to match label selector %v, but got %v\n", labelSelector, namespaceList.Items)
+	if len(namespaceList.Items) == 0 {
+		logger.Info("No namespaces matched label selector", "source", req.NamespacedName, "labelSelector", labelSelector)
+	}
 
-		return ctrl.Result{}, nil
+	if !controllerutil.ContainsFinalizer(&secret, finalizerName) {
+		controllerutil.AddFinalizer(&secret, finalizerName)
+		if err := r.Update(ctx, &secret); err != nil {
+			return ctrl.Result{}, err
+		}
 	}
 
-	// list namespaces which match the labelselector in annotationValue
+	matchedNamespaces := make(map[string]bool, len(namespaceList.Items))
+	for _, namespace := range namespaceList.Items {
+		matchedNamespaces[namespace.Name] = true
 
-	newNamespace := namespaceList.Items[0].Name
-	newSecretName := secret.Name
+		destination := types.NamespacedName{Namespace: namespace.Name, Name: secret.Name}
+		logger.Info("Triggering sync for secret", "source", req.NamespacedName, "destination", destination, "resourceVersion", secret.ResourceVersion)
 
-	fmt.Printf("Triggering sync for secret %s/%s\n", secret.Namespace, secret.Name)
+		if err := r.copySecret(ctx, &secret, namespace.Name, secret.Name); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
 
-	if err := r.copySecret(ctx, &secret, newNamespace, newSecretName); err != nil {
+	if err := r.gcSyncedCopies(ctx, &secret, matchedNamespaces); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{}, r.recordSyncedTo(ctx, &secret, matchedNamespaces)
 }
 
-func (r *SecretController) copySecret(ctx context.Context, secret *v1.Secret, newNamespace string, newSecretName string) error {
-	var newSecret v1.Secret
-	newSecretKey := types.NamespacedName{Name: newSecretName, Namespace: newNamespace}
-	err := r.Get(ctx, newSecretKey, &newSecret)
-	if err != nil && !errors.IsNotFound(err) {
+// gcSyncedCopies deletes previously-created copies of secret whose target
+// namespace is no longer present in keep, using the
+// "kubed.appscode.com/synced-to" bookkeeping annotation. Passing an empty
+// keep set garbage-collects every copy, which is what happens when secret
+// itself is being deleted.
+func (r *SecretController) gcSyncedCopies(ctx context.Context, secret *v1.Secret, keep map[string]bool) error {
+	syncedTo, found := secret.Annotations["kubed.appscode.com/synced-to"]
+	if !found {
+		return nil
+	}
+
+	var previousNamespaces []string
+	if err := json.Unmarshal([]byte(syncedTo), &previousNamespaces); err != nil {
 		return err
 	}
 
-	secretExists := !errors.IsNotFound(err)
+	for _, namespace := range previousNamespaces {
+		if keep[namespace] {
+			continue
+		}
+
+		log.FromContext(ctx).Info("Garbage collecting stale sync", "source", types.NamespacedName{Namespace: secret.Namespace, Name: secret.Name}, "destination", types.NamespacedName{Namespace: namespace, Name: secret.Name})
 
-	newSecretData := make(map[string][]byte)
-	for key, value := range secret.Data {
-		newSecretData[key] = value
+		stale := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secret.Name,
+				Namespace: namespace,
+			},
+		}
+		if err := r.Delete(ctx, stale); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
 	}
 
-	if secretExists {
-		newSecret.Data = newSecretData
+	return nil
+}
 
-		existingOrigin, exists := newSecret.Annotations["kubed.appscode.com/origin"]
-		if exists {
-			var originObj origin
-			if err := json.Unmarshal([]byte(existingOrigin), &originObj); err != nil {
-				return err
-			}
+// recordSyncedTo persists the set of namespaces secret was just fanned out
+// to, so the next reconcile can detect namespaces that dropped out of the
+// selector and garbage-collect the copies left behind in them.
+func (r *SecretController) recordSyncedTo(ctx context.Context, secret *v1.Secret, matchedNamespaces map[string]bool) error {
+	namespaces := make([]string, 0, len(matchedNamespaces))
+	for namespace := range matchedNamespaces {
+		namespaces = append(namespaces, namespace)
+	}
 
-			if secret.Namespace == newSecret.Namespace && secret.Name == newSecret.Name {
-				fmt.Printf("Ignoring circular sync for secret %s/%s to %s/%s\n", secret.Namespace, secret.Name, newNamespace, newSecretName)
-				// This is a circular sync, ignore
-				return nil
-			}
+	syncedToBytes, err := json.Marshal(namespaces)
+	if err != nil {
+		return err
+	}
 
-			originVersion, err := strconv.ParseInt(originObj.ResourceVersion, 10, 64)
-			if nil != err {
-				return err
-			}
-			secretVersion, err := strconv.ParseInt(originObj.ResourceVersion, 10, 64)
-			if nil != err {
+	if secret.Annotations["kubed.appscode.com/synced-to"] == string(syncedToBytes) {
+		return nil
+	}
+
+	if secret.Annotations == nil {
+		secret.Annotations = make(map[string]string)
+	}
+	secret.Annotations["kubed.appscode.com/synced-to"] = string(syncedToBytes)
+
+	return r.Update(ctx, secret)
+}
+
+func (r *SecretController) copySecret(ctx context.Context, secret *v1.Secret, newNamespace string, newSecretName string) error {
+	logger := log.FromContext(ctx)
+	source := types.NamespacedName{Namespace: secret.Namespace, Name: secret.Name}
+	destination := types.NamespacedName{Namespace: newNamespace, Name: newSecretName}
+
+	if secret.Namespace == newNamespace && secret.Name == newSecretName {
+		logger.Info("Ignoring circular sync", "source", source, "destination", destination, "reason", "source and destination are the same secret")
+		r.Recorder.Eventf(secret, v1.EventTypeWarning, "CircularSync", "Refused to sync %s to itself", source)
+		// This is a circular sync, ignore
+		return nil
+	}
+
+	var existing v1.Secret
+	err := r.Get(ctx, destination, &existing)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	if err == nil {
+		if existingOrigin, exists := existing.Annotations["kubed.appscode.com/origin"]; exists {
+			var originObj origin
+			if err := json.Unmarshal([]byte(existingOrigin), &originObj); err != nil {
 				return err
 			}
 
-			if originVersion >= secretVersion {
-				fmt.Printf("Ignoring outdated sync for secret %s/%s to %s/%s. Copy version %d origin version %d\n", secret.Namespace, secret.Name, newNamespace, newSecretName, originVersion, secretVersion)
-				// This has already been synced, ignore
+			if originObj.isCurrent(secret) {
+				// This is the common steady-state outcome of a reconcile, not a problem, so it's
+				// logged at low verbosity only; emitting a Warning event here would spam the
+				// fleet on every routine namespace event since mapNamespaceToSecrets requeues
+				// every annotation-bearing secret on any namespace change.
+				logger.V(1).Info("Ignoring already synced secret", "source", source, "destination", destination, "resourceVersion", secret.ResourceVersion, "reason", "destination already reflects this resourceVersion")
 				return nil
 			}
-
-		}
-	} else {
-		newSecret = v1.Secret{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      newSecretName,
-				Namespace: newNamespace,
-			},
-			Type: secret.Type,
-			Data: newSecretData,
 		}
 	}
 
@@ -150,31 +249,105 @@ func (r *SecretController) copySecret(ctx context.Context, secret *v1.Secret, ne
 		ResourceVersion: secret.ResourceVersion,
 	}
 	originBytes, err := json.Marshal(originObj)
-	if nil == newSecret.ObjectMeta.Annotations {
-		newSecret.ObjectMeta.Annotations = make(map[string]string)
+	if err != nil {
+		return err
+	}
+
+	destinationData := secret.Data
+	destinationType := secret.Type
+	if xform, err := r.transforms.forAnnotation(secret); err != nil {
+		return err
+	} else if xform != nil {
+		destinationData, destinationType, err = xform.apply(secret.Data, secret.Type)
+		if err != nil {
+			return err
+		}
 	}
-	if nil == newSecret.ObjectMeta.Labels {
-		newSecret.ObjectMeta.Labels = make(map[string]string)
+
+	applyConfig := applycorev1.Secret(newSecretName, newNamespace).
+		WithType(destinationType).
+		WithData(destinationData).
+		WithAnnotations(map[string]string{
+			"kubed.appscode.com/origin": string(originBytes),
+		}).
+		WithLabels(map[string]string{
+			"kubed.appscode.com/origin.cluster":   "unicorn",
+			"kubed.appscode.com/origin.name":      secret.Name,
+			"kubed.appscode.com/origin.namespace": secret.Namespace,
+		})
+
+	applyJSON, err := json.Marshal(applyConfig)
+	if err != nil {
+		return err
+	}
+
+	patch := &unstructured.Unstructured{}
+	if err := json.Unmarshal(applyJSON, &patch.Object); err != nil {
+		return err
 	}
-	newSecret.ObjectMeta.Annotations["kubed.appscode.com/origin"] = string(originBytes)
-	newSecret.ObjectMeta.Labels["kubed.appscode.com/origin.cluster"] = "unicorn"
-	newSecret.ObjectMeta.Labels["kubed.appscode.com/origin.name"] = secret.Name
-	newSecret.ObjectMeta.Labels["kubed.appscode.com/origin.namespace"] = secret.Namespace
 
-	if secretExists {
-		fmt.Printf("Updating existing secret: %s/%s\n", newSecret.Namespace, newSecret.Name)
+	logger.Info("Applying secret", "source", source, "destination", destination, "resourceVersion", secret.ResourceVersion)
 
-		return r.Client.Update(ctx, &newSecret)
+	if err := r.Patch(ctx, patch, client.Apply, client.FieldOwner(fieldOwner), client.ForceOwnership); err != nil {
+		return err
 	}
 
-	fmt.Printf("Creating new secret: %s/%s\n", newSecret.Namespace, newSecret.Name)
+	r.Recorder.Eventf(secret, v1.EventTypeNormal, "SyncedSecret", "Synced to %s at resourceVersion %s", destination, secret.ResourceVersion)
+	return nil
+}
+
+// updateSecretWithRetry retries an Update once after re-fetching the object
+// if the API server rejects it with a conflict, which is the standard
+// pattern for controllers that race with other writers on the same object.
+// Used by SecretSyncController to write destination secrets;
+// SecretController applies its destinations server-side instead.
+func updateSecretWithRetry(ctx context.Context, c client.Client, newSecret *v1.Secret) error {
+	err := c.Update(ctx, newSecret)
+	if err == nil || !errors.IsConflict(err) {
+		return err
+	}
 
-	return r.Client.Create(ctx, &newSecret)
+	var latest v1.Secret
+	key := types.NamespacedName{Name: newSecret.Name, Namespace: newSecret.Namespace}
+	if getErr := c.Get(ctx, key, &latest); getErr != nil {
+		return getErr
+	}
+
+	newSecret.ResourceVersion = latest.ResourceVersion
+
+	return c.Update(ctx, newSecret)
+}
+
+// mapNamespaceToSecrets re-queues every source secret carrying the sync
+// annotation whenever a namespace's labels change, so newly-labeled
+// namespaces receive the secret without waiting for the source to change.
+func (r *SecretController) mapNamespaceToSecrets(ctx context.Context, _ client.Object) []reconcile.Request {
+	var secretList v1.SecretList
+	if err := r.List(ctx, &secretList); err != nil {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, secret := range secretList.Items {
+		if _, found := secret.Annotations["kubed.appscode.com/sync"]; !found {
+			continue
+		}
+
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: secret.Namespace, Name: secret.Name},
+		})
+	}
+
+	return requests
 }
 
 // SetupWithManager sets up the controller with a manager.
 func (r *SecretController) SetupWithManager(mgr ctrl.Manager) error {
+	r.Recorder = mgr.GetEventRecorderFor(fieldOwner)
+	r.transforms = newTransformCache()
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1.Secret{}).
+		Watches(&v1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.mapNamespaceToSecrets)).
 		Complete(r)
 }
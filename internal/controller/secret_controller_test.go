@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestOriginIsCurrent(t *testing.T) {
+	cases := []struct {
+		name   string
+		origin origin
+		secret *v1.Secret
+		want   bool
+	}{
+		{
+			name:   "matching uid and resourceVersion",
+			origin: origin{Uid: "abc-123", ResourceVersion: "42"},
+			secret: secretWithUIDAndVersion("abc-123", "42"),
+			want:   true,
+		},
+		{
+			name:   "different resourceVersion",
+			origin: origin{Uid: "abc-123", ResourceVersion: "42"},
+			secret: secretWithUIDAndVersion("abc-123", "43"),
+			want:   false,
+		},
+		{
+			name:   "secret deleted and recreated with the same resourceVersion string",
+			origin: origin{Uid: "abc-123", ResourceVersion: "42"},
+			secret: secretWithUIDAndVersion("def-456", "42"),
+			want:   false,
+		},
+		{
+			name:   "resourceVersion that looks numerically smaller is still a mismatch",
+			origin: origin{Uid: "abc-123", ResourceVersion: "100"},
+			secret: secretWithUIDAndVersion("abc-123", "99"),
+			want:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.origin.isCurrent(tc.secret); got != tc.want {
+				t.Errorf("isCurrent() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func secretWithUIDAndVersion(uid, resourceVersion string) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			UID:             types.UID(uid),
+			ResourceVersion: resourceVersion,
+		},
+	}
+}
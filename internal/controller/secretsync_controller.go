@@ -0,0 +1,325 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	secretsyncv1alpha1 "github.com/szeber/secret-sync/api/v1alpha1"
+)
+
+// secretRefIndexKey indexes SecretSync resources by the "namespace/name" of the source Secret
+// they reference, so Secret watch events can be mapped back to the SecretSync resources that
+// care about them without listing every SecretSync on each event.
+const secretRefIndexKey = ".spec.secretRef"
+
+// secretSyncRecorderName identifies SecretSyncController's events, distinct from the legacy
+// annotation-compat controller's.
+const secretSyncRecorderName = "secretsync-controller"
+
+// secretSyncFinalizerName is set on SecretSync resources so that deletion can be intercepted to
+// garbage-collect the destination Secrets it fanned out, mirroring finalizerName on the
+// annotation-compat path in secret_controller.go.
+const secretSyncFinalizerName = "secretsync.szeber.dev/finalizer"
+
+// SecretSyncController implements a controller for the SecretSync custom resource. It is the
+// typed, declarative counterpart to SecretController's annotation-driven path.
+type SecretSyncController struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	transforms *transformCache
+}
+
+// +kubebuilder:rbac:groups=secretsync.szeber.dev,resources=secretsyncs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=secretsync.szeber.dev,resources=secretsyncs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// Reconcile handles requests to reconcile SecretSync resources.
+func (r *SecretSyncController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var sync secretsyncv1alpha1.SecretSync
+	if err := r.Get(ctx, req.NamespacedName, &sync); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !sync.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(&sync, secretSyncFinalizerName) {
+			return ctrl.Result{}, nil
+		}
+
+		if err := r.gcTargets(ctx, &sync, map[string]bool{}); err != nil {
+			return ctrl.Result{}, err
+		}
+		if sync.Status.SourceUID != "" {
+			r.transforms.evict(types.UID(sync.Status.SourceUID))
+		}
+
+		controllerutil.RemoveFinalizer(&sync, secretSyncFinalizerName)
+		return ctrl.Result{}, r.Update(ctx, &sync)
+	}
+
+	if !controllerutil.ContainsFinalizer(&sync, secretSyncFinalizerName) {
+		controllerutil.AddFinalizer(&sync, secretSyncFinalizerName)
+		if err := r.Update(ctx, &sync); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	var source v1.Secret
+	sourceKey := types.NamespacedName{Namespace: sync.Spec.SecretRef.Namespace, Name: sync.Spec.SecretRef.Name}
+	if err := r.Get(ctx, sourceKey, &source); err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Info("source secret gone, garbage collecting targets", "source", sourceKey, "secretSync", req.NamespacedName)
+			if sync.Status.SourceUID != "" {
+				r.transforms.evict(types.UID(sync.Status.SourceUID))
+			}
+			return ctrl.Result{}, r.gcTargets(ctx, &sync, map[string]bool{})
+		}
+		return ctrl.Result{}, err
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&sync.Spec.DestinationNamespaceSelector)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var namespaceList v1.NamespaceList
+	if err := r.List(ctx, &namespaceList, &client.ListOptions{LabelSelector: selector}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	destinationName := sync.Spec.SecretRef.Name
+	if sync.Spec.DestinationNameTemplate != "" {
+		destinationName = sync.Spec.DestinationNameTemplate
+	}
+
+	destinationType := source.Type
+	if sync.Spec.Type != "" {
+		destinationType = sync.Spec.Type
+	}
+
+	data := filterKeys(source.Data, sync.Spec.Keys)
+
+	if xform, err := r.transforms.forSpec(source.UID, source.ResourceVersion, sync.Spec.Transform); err != nil {
+		return ctrl.Result{}, err
+	} else if xform != nil {
+		data, destinationType, err = xform.apply(data, destinationType)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	contentHash := syncContentHash(destinationName, destinationType, data)
+
+	prevTargets := make(map[string]secretsyncv1alpha1.SyncTarget, len(sync.Status.Targets))
+	for _, target := range sync.Status.Targets {
+		prevTargets[target.Namespace] = target
+	}
+
+	matchedNamespaces := make(map[string]bool, len(namespaceList.Items))
+	targets := make([]secretsyncv1alpha1.SyncTarget, 0, len(namespaceList.Items))
+	for _, namespace := range namespaceList.Items {
+		matchedNamespaces[namespace.Name] = true
+
+		// Skip the re-copy only when the destination Secret this reconcile would produce is
+		// byte-for-byte what was last written: ContentHash covers the destination name, type and
+		// data together, so it changes whenever spec.transform, spec.keys, spec.type or
+		// spec.destinationNameTemplate change, even if the source Secret itself didn't. A changed
+		// source UID (the source was deleted and recreated) always forces a re-copy.
+		if prev, ok := prevTargets[namespace.Name]; ok && prev.Ready && prev.ContentHash == contentHash && sync.Status.SourceUID == string(source.UID) {
+			targets = append(targets, prev)
+			continue
+		}
+
+		target := secretsyncv1alpha1.SyncTarget{Namespace: namespace.Name, Name: destinationName}
+		if err := r.copyToTarget(ctx, namespace.Name, destinationName, destinationType, data); err != nil {
+			target.Ready = false
+			target.Error = err.Error()
+			logger.Error(err, "failed syncing SecretSync to namespace", "secretSync", req.NamespacedName, "namespace", namespace.Name)
+			r.Recorder.Eventf(&source, v1.EventTypeWarning, "SyncFailed", "Failed syncing to namespace %s: %v", namespace.Name, err)
+		} else {
+			target.Ready = true
+			target.LastSyncedResourceVersion = source.ResourceVersion
+			target.ContentHash = contentHash
+			r.Recorder.Eventf(&source, v1.EventTypeNormal, "SyncedSecret", "Synced to %s/%s", namespace.Name, destinationName)
+		}
+		targets = append(targets, target)
+	}
+
+	if err := r.gcTargets(ctx, &sync, matchedNamespaces); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	sync.Status.SourceUID = string(source.UID)
+	sync.Status.Targets = targets
+
+	return ctrl.Result{}, r.Status().Update(ctx, &sync)
+}
+
+// filterKeys returns the subset of data allowed through a SecretSync's key allow/deny list. A nil
+// filter copies every key unchanged.
+func filterKeys(data map[string][]byte, filter *secretsyncv1alpha1.KeyFilter) map[string][]byte {
+	filtered := make(map[string][]byte, len(data))
+	for key, value := range data {
+		if filter != nil && len(filter.Allow) > 0 && !containsString(filter.Allow, key) {
+			continue
+		}
+		if filter != nil && containsString(filter.Deny, key) {
+			continue
+		}
+		filtered[key] = value
+	}
+	return filtered
+}
+
+// syncContentHash fingerprints the destination Secret a SecretSync reconcile would write: its
+// name, type and data. Reconcile compares this against SyncTarget.ContentHash to decide whether a
+// target still reflects the current source Secret and SecretSync spec together, so edits to
+// spec.transform, spec.keys, spec.type or spec.destinationNameTemplate are detected even when the
+// source Secret itself hasn't changed.
+func syncContentHash(name string, secretType v1.SecretType, data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00", name, secretType)
+	for _, key := range keys {
+		fmt.Fprintf(h, "%s\x00%x\x00", key, data[key])
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+// copyToTarget creates or updates the destination Secret in namespace with data, leaving any
+// annotations or labels other controllers or users have added to it untouched.
+func (r *SecretSyncController) copyToTarget(ctx context.Context, namespace string, name string, secretType v1.SecretType, data map[string][]byte) error {
+	var destination v1.Secret
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	err := r.Get(ctx, key, &destination)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	if apierrors.IsNotFound(err) {
+		destination = v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Type:       secretType,
+			Data:       data,
+		}
+		return r.Create(ctx, &destination)
+	}
+
+	destination.Type = secretType
+	destination.Data = data
+
+	return updateSecretWithRetry(ctx, r.Client, &destination)
+}
+
+// gcTargets deletes destination secrets recorded in sync's status whose namespace is no longer
+// present in keep. Passing an empty keep set garbage-collects every target, which is what happens
+// once the source Secret has been deleted.
+func (r *SecretSyncController) gcTargets(ctx context.Context, sync *secretsyncv1alpha1.SecretSync, keep map[string]bool) error {
+	for _, target := range sync.Status.Targets {
+		if keep[target.Namespace] {
+			continue
+		}
+
+		stale := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: target.Name, Namespace: target.Namespace},
+		}
+		if err := r.Delete(ctx, stale); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mapSecretToSyncs re-queues every SecretSync that references secret as its source, so edits to
+// the source Secret trigger a fan-out without waiting for the SecretSync itself to change.
+func (r *SecretSyncController) mapSecretToSyncs(ctx context.Context, obj client.Object) []reconcile.Request {
+	secret, ok := obj.(*v1.Secret)
+	if !ok {
+		return nil
+	}
+
+	var syncList secretsyncv1alpha1.SecretSyncList
+	if err := r.List(ctx, &syncList, client.MatchingFields{secretRefIndexKey: secret.Namespace + "/" + secret.Name}); err != nil {
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(syncList.Items))
+	for _, sync := range syncList.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: sync.Namespace, Name: sync.Name}})
+	}
+
+	return requests
+}
+
+// mapNamespaceToSyncs re-queues every SecretSync whenever a namespace's labels change, so newly
+// matching namespaces receive the source Secret without waiting for it to change.
+func (r *SecretSyncController) mapNamespaceToSyncs(ctx context.Context, _ client.Object) []reconcile.Request {
+	var syncList secretsyncv1alpha1.SecretSyncList
+	if err := r.List(ctx, &syncList); err != nil {
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(syncList.Items))
+	for _, sync := range syncList.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: sync.Namespace, Name: sync.Name}})
+	}
+
+	return requests
+}
+
+// SetupWithManager sets up the controller with a manager.
+func (r *SecretSyncController) SetupWithManager(mgr ctrl.Manager) error {
+	r.transforms = newTransformCache()
+	r.Recorder = mgr.GetEventRecorderFor(secretSyncRecorderName)
+
+	err := mgr.GetFieldIndexer().IndexField(context.Background(), &secretsyncv1alpha1.SecretSync{}, secretRefIndexKey, func(obj client.Object) []string {
+		sync := obj.(*secretsyncv1alpha1.SecretSync)
+		return []string{sync.Spec.SecretRef.Namespace + "/" + sync.Spec.SecretRef.Name}
+	})
+	if err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&secretsyncv1alpha1.SecretSync{}).
+		Watches(&v1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.mapSecretToSyncs)).
+		Watches(&v1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.mapNamespaceToSyncs)).
+		Complete(r)
+}
@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	secretsyncv1alpha1 "github.com/szeber/secret-sync/api/v1alpha1"
+)
+
+func newSecretSyncTestController(t *testing.T, objs ...client.Object) (*SecretSyncController, client.Client) {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	if err := v1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme(core/v1): %v", err)
+	}
+	if err := secretsyncv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme(v1alpha1): %v", err)
+	}
+
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithStatusSubresource(&secretsyncv1alpha1.SecretSync{}).
+		WithObjects(objs...).
+		Build()
+
+	return &SecretSyncController{
+		Client:     c,
+		Scheme:     scheme,
+		Recorder:   record.NewFakeRecorder(10),
+		transforms: newTransformCache(),
+	}, c
+}
+
+func TestSecretSyncControllerReconcile_SpecChangeForcesRecopy(t *testing.T) {
+	ctx := context.Background()
+
+	source := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "src"},
+		Data:       map[string][]byte{"password": []byte("hunter2")},
+	}
+	ns := &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "dst", Labels: map[string]string{"sync": "true"}},
+	}
+	sync := &secretsyncv1alpha1.SecretSync{
+		ObjectMeta: metav1.ObjectMeta{Name: "creds-sync", Namespace: "src"},
+		Spec: secretsyncv1alpha1.SecretSyncSpec{
+			SecretRef:                    secretsyncv1alpha1.SecretReference{Namespace: "src", Name: "creds"},
+			DestinationNamespaceSelector: metav1.LabelSelector{MatchLabels: map[string]string{"sync": "true"}},
+		},
+	}
+
+	r, c := newSecretSyncTestController(t, source, ns, sync)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "src", Name: "creds-sync"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("initial Reconcile() error = %v", err)
+	}
+
+	var destination v1.Secret
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "dst", Name: "creds"}, &destination); err != nil {
+		t.Fatalf("Get(destination) after initial sync: %v", err)
+	}
+	if string(destination.Data["password"]) != "hunter2" {
+		t.Fatalf("destination.Data[password] = %q, want %q", destination.Data["password"], "hunter2")
+	}
+
+	var updatedSync secretsyncv1alpha1.SecretSync
+	if err := c.Get(ctx, req.NamespacedName, &updatedSync); err != nil {
+		t.Fatalf("Get(SecretSync) after initial sync: %v", err)
+	}
+	if len(updatedSync.Status.Targets) != 1 || !updatedSync.Status.Targets[0].Ready {
+		t.Fatalf("Status.Targets after initial sync = %+v, want one ready target", updatedSync.Status.Targets)
+	}
+	firstHash := updatedSync.Status.Targets[0].ContentHash
+	if firstHash == "" {
+		t.Fatalf("Status.Targets[0].ContentHash is empty after initial sync")
+	}
+
+	// Reconciling again with nothing changed must not touch the destination: this is the
+	// steady-state no-op the content-hash skip exists for.
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("no-op Reconcile() error = %v", err)
+	}
+
+	// Edit the SecretSync's own spec, without touching the source Secret, to rename the key the
+	// destination receives. Without comparing against the computed content, the skip optimization
+	// would never re-copy and the destination would keep serving the stale key name forever.
+	if err := c.Get(ctx, req.NamespacedName, &updatedSync); err != nil {
+		t.Fatalf("Get(SecretSync) before spec change: %v", err)
+	}
+	updatedSync.Spec.Transform = &secretsyncv1alpha1.TransformSpec{
+		Rename: map[string]string{"password": "pass"},
+	}
+	if err := c.Update(ctx, &updatedSync); err != nil {
+		t.Fatalf("Update(SecretSync) with new transform: %v", err)
+	}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() after spec change error = %v", err)
+	}
+
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "dst", Name: "creds"}, &destination); err != nil {
+		t.Fatalf("Get(destination) after spec change: %v", err)
+	}
+	if _, stillPresent := destination.Data["password"]; stillPresent {
+		t.Errorf("destination.Data still has the old key %q after rename", "password")
+	}
+	if string(destination.Data["pass"]) != "hunter2" {
+		t.Errorf("destination.Data[pass] = %q, want %q", destination.Data["pass"], "hunter2")
+	}
+
+	if err := c.Get(ctx, req.NamespacedName, &updatedSync); err != nil {
+		t.Fatalf("Get(SecretSync) after spec change: %v", err)
+	}
+	if len(updatedSync.Status.Targets) != 1 {
+		t.Fatalf("Status.Targets after spec change = %+v, want one target", updatedSync.Status.Targets)
+	}
+	if updatedSync.Status.Targets[0].ContentHash == firstHash {
+		t.Errorf("ContentHash did not change after the spec-driven content change")
+	}
+}
+
+func TestSecretSyncControllerReconcile_DeleteGarbageCollectsTargets(t *testing.T) {
+	ctx := context.Background()
+
+	source := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "src"},
+		Data:       map[string][]byte{"password": []byte("hunter2")},
+	}
+	ns := &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "dst", Labels: map[string]string{"sync": "true"}},
+	}
+	sync := &secretsyncv1alpha1.SecretSync{
+		ObjectMeta: metav1.ObjectMeta{Name: "creds-sync", Namespace: "src"},
+		Spec: secretsyncv1alpha1.SecretSyncSpec{
+			SecretRef:                    secretsyncv1alpha1.SecretReference{Namespace: "src", Name: "creds"},
+			DestinationNamespaceSelector: metav1.LabelSelector{MatchLabels: map[string]string{"sync": "true"}},
+		},
+	}
+
+	r, c := newSecretSyncTestController(t, source, ns, sync)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "src", Name: "creds-sync"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("initial Reconcile() error = %v", err)
+	}
+
+	var toDelete secretsyncv1alpha1.SecretSync
+	if err := c.Get(ctx, req.NamespacedName, &toDelete); err != nil {
+		t.Fatalf("Get(SecretSync): %v", err)
+	}
+	if !controllerContainsFinalizer(toDelete.Finalizers, secretSyncFinalizerName) {
+		t.Fatalf("SecretSync.Finalizers = %v, want it to contain %q", toDelete.Finalizers, secretSyncFinalizerName)
+	}
+
+	if err := c.Delete(ctx, &toDelete); err != nil {
+		t.Fatalf("Delete(SecretSync): %v", err)
+	}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() after delete error = %v", err)
+	}
+
+	var destination v1.Secret
+	err := c.Get(ctx, types.NamespacedName{Namespace: "dst", Name: "creds"}, &destination)
+	if err == nil {
+		t.Fatalf("destination Secret still exists after SecretSync deletion")
+	}
+}
+
+func controllerContainsFinalizer(finalizers []string, name string) bool {
+	return containsString(finalizers, name)
+}
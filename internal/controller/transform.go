@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"text/template"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+
+	secretsyncv1alpha1 "github.com/szeber/secret-sync/api/v1alpha1"
+)
+
+// transformAnnotation configures the transform pipeline on the annotation-compat path. Its value
+// is YAML (a superset of JSON) unmarshalled into the same TransformSpec the SecretSync CRD uses,
+// so both configuration paths share one engine.
+const transformAnnotation = "kubed.appscode.com/transform"
+
+// transform is a parsed, ready-to-run TransformSpec: its Go templates are pre-compiled so the
+// reconcile hot path never re-parses template source.
+type transform struct {
+	spec      secretsyncv1alpha1.TransformSpec
+	templates map[string]*template.Template
+}
+
+// parseTransform compiles spec into a transform ready to be applied repeatedly.
+func parseTransform(spec secretsyncv1alpha1.TransformSpec) (*transform, error) {
+	templates := make(map[string]*template.Template, len(spec.Template))
+	for key, source := range spec.Template {
+		tmpl, err := template.New(key).Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("parsing template for key %q: %w", key, err)
+		}
+		templates[key] = tmpl
+	}
+
+	return &transform{spec: spec, templates: templates}, nil
+}
+
+// apply projects sourceData through the transform pipeline in order: rename, key filtering,
+// base64 re-encoding, template rendering, then resolves the destination secret type, coercing to
+// defaultType when the transform doesn't override it.
+func (t *transform) apply(sourceData map[string][]byte, defaultType v1.SecretType) (map[string][]byte, v1.SecretType, error) {
+	data := make(map[string][]byte, len(sourceData))
+	for key, value := range sourceData {
+		destKey := key
+		if renamed, ok := t.spec.Rename[key]; ok {
+			destKey = renamed
+		}
+		data[destKey] = value
+	}
+
+	if t.spec.Keys != nil {
+		for key := range data {
+			if len(t.spec.Keys.Allow) > 0 && !containsString(t.spec.Keys.Allow, key) {
+				delete(data, key)
+				continue
+			}
+			if containsString(t.spec.Keys.Deny, key) {
+				delete(data, key)
+			}
+		}
+	}
+
+	for _, key := range t.spec.Base64Encode {
+		value, ok := data[key]
+		if !ok {
+			continue
+		}
+		encoded := make([]byte, base64.StdEncoding.EncodedLen(len(value)))
+		base64.StdEncoding.Encode(encoded, value)
+		data[key] = encoded
+	}
+
+	if len(t.templates) > 0 {
+		templateContext := struct{ Data map[string]string }{Data: stringifyData(sourceData)}
+		for key, tmpl := range t.templates {
+			var rendered bytes.Buffer
+			if err := tmpl.Execute(&rendered, templateContext); err != nil {
+				return nil, "", fmt.Errorf("rendering template for key %q: %w", key, err)
+			}
+			data[key] = rendered.Bytes()
+		}
+	}
+
+	destType := defaultType
+	if t.spec.Type != "" {
+		destType = t.spec.Type
+	}
+
+	if destType == v1.SecretTypeDockerConfigJson {
+		if len(data[v1.DockerConfigJsonKey]) == 0 {
+			return nil, "", fmt.Errorf("transform produced secret type %s but key %q is empty", destType, v1.DockerConfigJsonKey)
+		}
+	}
+
+	return data, destType, nil
+}
+
+func stringifyData(data map[string][]byte) map[string]string {
+	stringified := make(map[string]string, len(data))
+	for key, value := range data {
+		stringified[key] = string(value)
+	}
+	return stringified
+}
+
+// transformCache parses a source secret's transform configuration once per resourceVersion (and,
+// for a SecretSync, once per transform spec) and reuses it on subsequent reconciles of the same secret, so the hot path doesn't re-parse YAML or
+// recompile Go templates when nothing has changed.
+type transformCache struct {
+	mu      sync.Mutex
+	entries map[types.UID]*transformCacheEntry
+}
+
+type transformCacheEntry struct {
+	resourceVersion string
+	transform       *transform
+}
+
+func newTransformCache() *transformCache {
+	return &transformCache{entries: make(map[types.UID]*transformCacheEntry)}
+}
+
+// evict drops the cached transform for uid, if any. Callers must evict once a source Secret or
+// SecretSync is deleted, otherwise entries accumulates one entry per UID ever seen for
+// the lifetime of the process.
+func (c *transformCache) evict(uid types.UID) {
+	c.mu.Lock()
+	delete(c.entries, uid)
+	c.mu.Unlock()
+}
+
+// forAnnotation returns the parsed transform for a secret carrying the transform annotation, or
+// nil if the annotation isn't set.
+func (c *transformCache) forAnnotation(secret *v1.Secret) (*transform, error) {
+	raw, found := secret.Annotations[transformAnnotation]
+	if !found {
+		return nil, nil
+	}
+
+	return c.get(secret.UID, secret.ResourceVersion, func() (secretsyncv1alpha1.TransformSpec, error) {
+		var spec secretsyncv1alpha1.TransformSpec
+		if err := yaml.Unmarshal([]byte(raw), &spec); err != nil {
+			return spec, fmt.Errorf("parsing %s annotation: %w", transformAnnotation, err)
+		}
+		return spec, nil
+	})
+}
+
+// forSpec returns the parsed transform for a SecretSync's Transform field, or nil if unset.
+func (c *transformCache) forSpec(sourceUID types.UID, sourceResourceVersion string, spec *secretsyncv1alpha1.TransformSpec) (*transform, error) {
+	if spec == nil {
+		return nil, nil
+	}
+
+	// The spec itself is part of the cache key, so an edited transform is re-parsed even while the
+	// source Secret's resourceVersion stays the same.
+	specKey, err := yaml.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.get(sourceUID, sourceResourceVersion+"/"+string(specKey), func() (secretsyncv1alpha1.TransformSpec, error) {
+		return *spec, nil
+	})
+}
+
+func (c *transformCache) get(uid types.UID, resourceVersion string, load func() (secretsyncv1alpha1.TransformSpec, error)) (*transform, error) {
+	c.mu.Lock()
+	entry, found := c.entries[uid]
+	c.mu.Unlock()
+	if found && entry.resourceVersion == resourceVersion {
+		return entry.transform, nil
+	}
+
+	spec, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := parseTransform(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[uid] = &transformCacheEntry{resourceVersion: resourceVersion, transform: parsed}
+	c.mu.Unlock()
+
+	return parsed, nil
+}
@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+
+	secretsyncv1alpha1 "github.com/szeber/secret-sync/api/v1alpha1"
+)
+
+func TestTransformApply(t *testing.T) {
+	cases := []struct {
+		name        string
+		spec        secretsyncv1alpha1.TransformSpec
+		data        map[string][]byte
+		defaultType v1.SecretType
+		wantData    map[string][]byte
+		wantType    v1.SecretType
+		wantErr     bool
+	}{
+		{
+			name:        "no-op transform keeps data and default type",
+			spec:        secretsyncv1alpha1.TransformSpec{},
+			data:        map[string][]byte{"password": []byte("hunter2")},
+			defaultType: v1.SecretTypeOpaque,
+			wantData:    map[string][]byte{"password": []byte("hunter2")},
+			wantType:    v1.SecretTypeOpaque,
+		},
+		{
+			name: "rename maps source keys to destination keys",
+			spec: secretsyncv1alpha1.TransformSpec{
+				Rename: map[string]string{"password": "pass"},
+			},
+			data:        map[string][]byte{"password": []byte("hunter2")},
+			defaultType: v1.SecretTypeOpaque,
+			wantData:    map[string][]byte{"pass": []byte("hunter2")},
+			wantType:    v1.SecretTypeOpaque,
+		},
+		{
+			name: "allow filter keeps only listed keys",
+			spec: secretsyncv1alpha1.TransformSpec{
+				Keys: &secretsyncv1alpha1.KeyFilter{Allow: []string{"username"}},
+			},
+			data:        map[string][]byte{"username": []byte("admin"), "password": []byte("hunter2")},
+			defaultType: v1.SecretTypeOpaque,
+			wantData:    map[string][]byte{"username": []byte("admin")},
+			wantType:    v1.SecretTypeOpaque,
+		},
+		{
+			name: "deny filter wins over allow",
+			spec: secretsyncv1alpha1.TransformSpec{
+				Keys: &secretsyncv1alpha1.KeyFilter{Allow: []string{"username", "password"}, Deny: []string{"password"}},
+			},
+			data:        map[string][]byte{"username": []byte("admin"), "password": []byte("hunter2")},
+			defaultType: v1.SecretTypeOpaque,
+			wantData:    map[string][]byte{"username": []byte("admin")},
+			wantType:    v1.SecretTypeOpaque,
+		},
+		{
+			name: "base64Encode re-encodes the destination value",
+			spec: secretsyncv1alpha1.TransformSpec{
+				Base64Encode: []string{"password"},
+			},
+			data:        map[string][]byte{"password": []byte("hunter2")},
+			defaultType: v1.SecretTypeOpaque,
+			wantData:    map[string][]byte{"password": []byte("aHVudGVyMg==")},
+			wantType:    v1.SecretTypeOpaque,
+		},
+		{
+			name: "template renders destination value from source data",
+			spec: secretsyncv1alpha1.TransformSpec{
+				Template: map[string]string{"greeting": "hello {{ .Data.name }}"},
+			},
+			data:        map[string][]byte{"name": []byte("world")},
+			defaultType: v1.SecretTypeOpaque,
+			wantData:    map[string][]byte{"name": []byte("world"), "greeting": []byte("hello world")},
+			wantType:    v1.SecretTypeOpaque,
+		},
+		{
+			name: "type override coerces the destination type",
+			spec: secretsyncv1alpha1.TransformSpec{
+				Type: v1.SecretTypeTLS,
+			},
+			data:        map[string][]byte{"tls.crt": []byte("cert"), "tls.key": []byte("key")},
+			defaultType: v1.SecretTypeOpaque,
+			wantData:    map[string][]byte{"tls.crt": []byte("cert"), "tls.key": []byte("key")},
+			wantType:    v1.SecretTypeTLS,
+		},
+		{
+			name: "dockerconfigjson coercion succeeds when the key is present",
+			spec: secretsyncv1alpha1.TransformSpec{
+				Rename: map[string]string{"config": v1.DockerConfigJsonKey},
+				Type:   v1.SecretTypeDockerConfigJson,
+			},
+			data:        map[string][]byte{"config": []byte(`{"auths":{}}`)},
+			defaultType: v1.SecretTypeOpaque,
+			wantData:    map[string][]byte{v1.DockerConfigJsonKey: []byte(`{"auths":{}}`)},
+			wantType:    v1.SecretTypeDockerConfigJson,
+		},
+		{
+			name: "dockerconfigjson coercion fails when the key is missing",
+			spec: secretsyncv1alpha1.TransformSpec{
+				Type: v1.SecretTypeDockerConfigJson,
+			},
+			data:        map[string][]byte{"password": []byte("hunter2")},
+			defaultType: v1.SecretTypeOpaque,
+			wantErr:     true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			xform, err := parseTransform(tc.spec)
+			if err != nil {
+				t.Fatalf("parseTransform() error = %v", err)
+			}
+
+			gotData, gotType, err := xform.apply(tc.data, tc.defaultType)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("apply() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("apply() error = %v", err)
+			}
+
+			if gotType != tc.wantType {
+				t.Errorf("apply() type = %v, want %v", gotType, tc.wantType)
+			}
+
+			if len(gotData) != len(tc.wantData) {
+				t.Fatalf("apply() data = %v, want %v", gotData, tc.wantData)
+			}
+			for key, want := range tc.wantData {
+				got, ok := gotData[key]
+				if !ok {
+					t.Errorf("apply() missing key %q", key)
+					continue
+				}
+				if string(got) != string(want) {
+					t.Errorf("apply()[%q] = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}